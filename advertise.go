@@ -0,0 +1,160 @@
+package ble
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+)
+
+const (
+	advertisingManagerInterface = "org.bluez.LEAdvertisingManager1"
+	advertisementInterface      = "org.bluez.LEAdvertisement1"
+)
+
+// ManufacturerDatum is one company's entry in an advertisement's
+// ManufacturerData. It is carried as an ordered slice, rather than a map,
+// so packet layout is stable across restarts.
+type ManufacturerDatum struct {
+	CompanyID uint16
+	Data      []byte
+}
+
+// AdvertisementOptions configures an LE advertisement registered through
+// Adapter.NewAdvertisement.
+type AdvertisementOptions struct {
+	LocalName        string
+	ServiceUUIDs     []string
+	ManufacturerData []ManufacturerDatum
+	ServiceData      map[string]dbus.Variant
+	Appearance       uint16
+	TxPower          bool
+}
+
+// Advertisement is a running LE advertisement registered with BlueZ.
+type Advertisement struct {
+	adapter *blob
+	path    dbus.ObjectPath
+}
+
+var advertisementSeq struct {
+	sync.Mutex
+	n int
+}
+
+func nextAdvertisementPath() dbus.ObjectPath {
+	advertisementSeq.Lock()
+	defer advertisementSeq.Unlock()
+	advertisementSeq.n++
+	return dbus.ObjectPath(fmt.Sprintf("/org/ble/advertisement%d", advertisementSeq.n))
+}
+
+// advertisementObject implements the Release method BlueZ calls when it
+// drops an advertisement.
+type advertisementObject struct{}
+
+func (*advertisementObject) Release() *dbus.Error {
+	return nil
+}
+
+// manufacturerDatumEntry is one (CompanyID, Data) dict entry of a
+// ManufacturerData property, laid out so it encodes identically to a
+// real a{qv} dict entry: the D-Bus wire format marshals STRUCT and
+// DICT_ENTRY containers the same way, so wrapping an ordered slice of
+// these in a Variant tagged with signature a{qv} preserves insertion
+// order on the wire, unlike a Go map, whose key iteration order the
+// encoder does not sort.
+type manufacturerDatumEntry struct {
+	CompanyID uint16
+	Data      dbus.Variant
+}
+
+var manufacturerDataSignature = dbus.ParseSignatureMust("a{qv}")
+
+// advertisementProperties implements org.freedesktop.DBus.Properties by
+// hand for one advertisement object. Every property is fixed at
+// registration and read-only, so there is nothing for the generic prop
+// package to add here, and handling ManufacturerData ourselves is what
+// lets it be returned as an ordered Variant instead of being re-encoded
+// from a map.
+type advertisementProperties struct {
+	values map[string]dbus.Variant
+}
+
+func (p *advertisementProperties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if iface != advertisementInterface {
+		return dbus.Variant{}, prop.ErrIfaceNotFound
+	}
+	v, ok := p.values[name]
+	if !ok {
+		return dbus.Variant{}, prop.ErrPropNotFound
+	}
+	return v, nil
+}
+
+func (p *advertisementProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != advertisementInterface {
+		return nil, prop.ErrIfaceNotFound
+	}
+	return p.values, nil
+}
+
+func (p *advertisementProperties) Set(iface, name string, value dbus.Variant) *dbus.Error {
+	return prop.ErrReadOnly
+}
+
+// NewAdvertisement exports opts as an org.bluez.LEAdvertisement1 object
+// and registers it with the adapter's LEAdvertisingManager1.
+func (adapter *blob) NewAdvertisement(opts AdvertisementOptions) (*Advertisement, error) {
+	path := nextAdvertisementPath()
+
+	serviceData := opts.ServiceData
+	if serviceData == nil {
+		serviceData = map[string]dbus.Variant{}
+	}
+	manufacturerData := make([]manufacturerDatumEntry, len(opts.ManufacturerData))
+	for i, m := range opts.ManufacturerData {
+		manufacturerData[i] = manufacturerDatumEntry{m.CompanyID, dbus.MakeVariant(m.Data)}
+	}
+
+	values := map[string]dbus.Variant{
+		"Type":             dbus.MakeVariant("peripheral"),
+		"LocalName":        dbus.MakeVariant(opts.LocalName),
+		"ServiceUUIDs":     dbus.MakeVariant(opts.ServiceUUIDs),
+		"ManufacturerData": dbus.MakeVariantWithSignature(manufacturerData, manufacturerDataSignature),
+		"ServiceData":      dbus.MakeVariant(serviceData),
+		"Appearance":       dbus.MakeVariant(opts.Appearance),
+		"IncludeTxPower":   dbus.MakeVariant(opts.TxPower),
+	}
+
+	if err := adapter.conn.bus.Export(&advertisementProperties{values}, path, "org.freedesktop.DBus.Properties"); err != nil {
+		return nil, err
+	}
+	if err := adapter.conn.bus.Export(&advertisementObject{}, path, advertisementInterface); err != nil {
+		_ = adapter.conn.bus.Export(nil, path, "org.freedesktop.DBus.Properties")
+		return nil, err
+	}
+
+	call := adapter.conn.bus.Object("org.bluez", adapter.Path()).Call(
+		advertisingManagerInterface+".RegisterAdvertisement", 0, path, map[string]dbus.Variant{},
+	)
+	if call.Err != nil {
+		_ = adapter.conn.bus.Export(nil, path, advertisementInterface)
+		_ = adapter.conn.bus.Export(nil, path, "org.freedesktop.DBus.Properties")
+		return nil, call.Err
+	}
+
+	return &Advertisement{adapter: adapter, path: path}, nil
+}
+
+// Stop unregisters the advertisement and releases its exported object, so
+// a fresh advertisement can be registered in its place afterwards.
+func (a *Advertisement) Stop() error {
+	call := a.adapter.conn.bus.Object("org.bluez", a.adapter.Path()).Call(
+		advertisingManagerInterface+".UnregisterAdvertisement", 0, a.path,
+	)
+	_ = a.adapter.conn.bus.Export(nil, a.path, advertisementInterface)
+	_ = a.adapter.conn.bus.Export(nil, a.path, "org.freedesktop.DBus.Properties")
+	return call.Err
+}
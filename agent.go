@@ -0,0 +1,173 @@
+package ble
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	agentInterface        = "org.bluez.Agent1"
+	agentManagerInterface = "org.bluez.AgentManager1"
+)
+
+var agentPath = dbus.ObjectPath("/org/ble/agent")
+
+// Agent corresponds to the org.bluez.Agent1 interface that BlueZ calls
+// back into while pairing a device that isn't JustWorks.
+// See bluez/doc/agent-api.txt
+type Agent interface {
+	RequestPinCode(device dbus.ObjectPath) (string, error)
+	DisplayPinCode(device dbus.ObjectPath, pincode string) error
+	RequestPasskey(device dbus.ObjectPath) (uint32, error)
+	DisplayPasskey(device dbus.ObjectPath, passkey uint32, entered uint16) error
+	RequestConfirmation(device dbus.ObjectPath, passkey uint32) error
+	RequestAuthorization(device dbus.ObjectPath) error
+	AuthorizeService(device dbus.ObjectPath, uuid string) error
+	Cancel() error
+	Release() error
+}
+
+// agentObject adapts an Agent to the *dbus.Error return values godbus
+// requires of exported methods.
+type agentObject struct {
+	agent Agent
+}
+
+func (a *agentObject) RequestPinCode(device dbus.ObjectPath) (string, *dbus.Error) {
+	pin, err := a.agent.RequestPinCode(device)
+	if err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	return pin, nil
+}
+
+func (a *agentObject) DisplayPinCode(device dbus.ObjectPath, pincode string) *dbus.Error {
+	return toDbusError(a.agent.DisplayPinCode(device, pincode))
+}
+
+func (a *agentObject) RequestPasskey(device dbus.ObjectPath) (uint32, *dbus.Error) {
+	passkey, err := a.agent.RequestPasskey(device)
+	if err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+	return passkey, nil
+}
+
+func (a *agentObject) DisplayPasskey(device dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	return toDbusError(a.agent.DisplayPasskey(device, passkey, entered))
+}
+
+func (a *agentObject) RequestConfirmation(device dbus.ObjectPath, passkey uint32) *dbus.Error {
+	return toDbusError(a.agent.RequestConfirmation(device, passkey))
+}
+
+func (a *agentObject) RequestAuthorization(device dbus.ObjectPath) *dbus.Error {
+	return toDbusError(a.agent.RequestAuthorization(device))
+}
+
+func (a *agentObject) AuthorizeService(device dbus.ObjectPath, uuid string) *dbus.Error {
+	return toDbusError(a.agent.AuthorizeService(device, uuid))
+}
+
+func (a *agentObject) Cancel() *dbus.Error {
+	return toDbusError(a.agent.Cancel())
+}
+
+func (a *agentObject) Release() *dbus.Error {
+	return toDbusError(a.agent.Release())
+}
+
+func toDbusError(err error) *dbus.Error {
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// RegisterAgent exports agent on the bus, registers it with BlueZ's
+// AgentManager1 under the given I/O capability (e.g. "KeyboardDisplay",
+// "DisplayYesNo", "NoInputNoOutput"), and requests it as the default
+// agent so Device.Pair can complete on devices that aren't JustWorks.
+func (conn *Connection) RegisterAgent(agent Agent, capability string) error {
+	if err := conn.bus.Export(&agentObject{agent}, agentPath, agentInterface); err != nil {
+		return err
+	}
+
+	manager := conn.bus.Object("org.bluez", dbus.ObjectPath("/org/bluez"))
+
+	call := manager.Call(agentManagerInterface+".RegisterAgent", 0, agentPath, capability)
+	if call.Err != nil {
+		_ = conn.bus.Export(nil, agentPath, agentInterface)
+		return call.Err
+	}
+
+	call = manager.Call(agentManagerInterface+".RequestDefaultAgent", 0, agentPath)
+	if call.Err != nil {
+		_ = manager.Call(agentManagerInterface+".UnregisterAgent", 0, agentPath)
+		_ = conn.bus.Export(nil, agentPath, agentInterface)
+		return call.Err
+	}
+	return nil
+}
+
+// UnregisterAgent unregisters the agent previously passed to
+// RegisterAgent and releases its exported object.
+func (conn *Connection) UnregisterAgent() error {
+	call := conn.bus.Object("org.bluez", dbus.ObjectPath("/org/bluez")).Call(
+		agentManagerInterface+".UnregisterAgent", 0, agentPath,
+	)
+	_ = conn.bus.Export(nil, agentPath, agentInterface)
+	return call.Err
+}
+
+// autoConfirmAgent accepts every pairing and authorization request
+// without prompting, for headless pairing.
+type autoConfirmAgent struct{}
+
+// NewAutoConfirmAgent returns an Agent that accepts every pairing and
+// authorization request without prompting, suitable for headless pairing.
+func NewAutoConfirmAgent() Agent {
+	return &autoConfirmAgent{}
+}
+
+func (*autoConfirmAgent) RequestPinCode(dbus.ObjectPath) (string, error)       { return "", nil }
+func (*autoConfirmAgent) DisplayPinCode(dbus.ObjectPath, string) error        { return nil }
+func (*autoConfirmAgent) RequestPasskey(dbus.ObjectPath) (uint32, error)      { return 0, nil }
+func (*autoConfirmAgent) DisplayPasskey(dbus.ObjectPath, uint32, uint16) error { return nil }
+func (*autoConfirmAgent) RequestConfirmation(dbus.ObjectPath, uint32) error    { return nil }
+func (*autoConfirmAgent) RequestAuthorization(dbus.ObjectPath) error           { return nil }
+func (*autoConfirmAgent) AuthorizeService(dbus.ObjectPath, string) error       { return nil }
+func (*autoConfirmAgent) Cancel() error                                       { return nil }
+func (*autoConfirmAgent) Release() error                                      { return nil }
+
+// pinAgent asks pin for a PIN code or passkey on every request, for
+// interactive pairing, and otherwise confirms everything it is asked.
+type pinAgent struct {
+	pin func() string
+}
+
+// NewPinAgent returns an Agent that calls pin to obtain a PIN code or
+// passkey for interactive pairing.
+func NewPinAgent(pin func() string) Agent {
+	return &pinAgent{pin: pin}
+}
+
+func (a *pinAgent) RequestPinCode(dbus.ObjectPath) (string, error) {
+	return a.pin(), nil
+}
+
+func (a *pinAgent) DisplayPinCode(dbus.ObjectPath, string) error { return nil }
+
+func (a *pinAgent) RequestPasskey(dbus.ObjectPath) (uint32, error) {
+	var passkey uint32
+	_, err := fmt.Sscanf(a.pin(), "%d", &passkey)
+	return passkey, err
+}
+
+func (a *pinAgent) DisplayPasskey(dbus.ObjectPath, uint32, uint16) error { return nil }
+func (a *pinAgent) RequestConfirmation(dbus.ObjectPath, uint32) error    { return nil }
+func (a *pinAgent) RequestAuthorization(dbus.ObjectPath) error           { return nil }
+func (a *pinAgent) AuthorizeService(dbus.ObjectPath, string) error       { return nil }
+func (a *pinAgent) Cancel() error                                        { return nil }
+func (a *pinAgent) Release() error                                       { return nil }
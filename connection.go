@@ -0,0 +1,191 @@
+package ble
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+// findChildren returns every cached object implementing iface whose
+// object path is nested under parent, e.g. the GATT services,
+// characteristics, and descriptors exported by a device. It walks
+// conn.objects the same way findObject's iterObjects does, since the
+// cache holds raw (path, Object) pairs straight off GetManagedObjects,
+// not *blob values.
+func (conn *Connection) findChildren(iface string, parent dbus.ObjectPath) ([]*blob, error) {
+	prefix := string(parent)
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	var children []*blob
+	for path, dict := range conn.objects {
+		props := dict[iface]
+		if props == nil {
+			continue
+		}
+		if !strings.HasPrefix(string(path), prefix) {
+			continue
+		}
+		children = append(children, &blob{conn: conn, path: path, iface: iface, properties: props})
+	}
+	return children, nil
+}
+
+// signalRule is a parsed subset of a D-Bus match rule: interface,
+// member, and either an exact path or a path_namespace prefix. It only
+// needs to match the rules this package itself constructs, not arbitrary
+// match rules.
+type signalRule struct {
+	iface         string
+	member        string
+	path          dbus.ObjectPath
+	pathNamespace string
+}
+
+func parseSignalRule(rule string) signalRule {
+	var r signalRule
+	for _, field := range strings.Split(rule, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], "'")
+		switch kv[0] {
+		case "interface":
+			r.iface = value
+		case "member":
+			r.member = value
+		case "path":
+			r.path = dbus.ObjectPath(value)
+		case "path_namespace":
+			r.pathNamespace = value
+		}
+	}
+	return r
+}
+
+func (r signalRule) String() string {
+	return fmt.Sprintf("interface=%q,member=%q,path=%q,path_namespace=%q", r.iface, r.member, r.path, r.pathNamespace)
+}
+
+func (r signalRule) matches(s *dbus.Signal) bool {
+	if r.iface != "" && !strings.HasPrefix(s.Name, r.iface+".") {
+		return false
+	}
+	if r.member != "" && !strings.HasSuffix(s.Name, "."+r.member) {
+		return false
+	}
+	if r.path != "" && s.Path != r.path {
+		return false
+	}
+	if r.pathNamespace != "" && !strings.HasPrefix(string(s.Path), r.pathNamespace) {
+		return false
+	}
+	return true
+}
+
+// signalDispatcher owns the single bus.Signal subscription for a
+// Connection and fans incoming signals out to subscribers registered
+// through Connection.Subscribe, so two watchers never steal signals from
+// each other.
+type signalDispatcher struct {
+	conn *Connection
+
+	mu          sync.Mutex
+	refCount    map[string]int
+	subscribers map[int]*ruleSubscriber
+	nextID      int
+}
+
+type ruleSubscriber struct {
+	rule signalRule
+	ch   chan *dbus.Signal
+}
+
+var (
+	dispatchersMu sync.Mutex
+	dispatchers   = map[*Connection]*signalDispatcher{}
+)
+
+func (conn *Connection) dispatcher() *signalDispatcher {
+	dispatchersMu.Lock()
+	defer dispatchersMu.Unlock()
+	d, ok := dispatchers[conn]
+	if !ok {
+		d = &signalDispatcher{
+			conn:        conn,
+			refCount:    map[string]int{},
+			subscribers: map[int]*ruleSubscriber{},
+		}
+		raw := make(chan *dbus.Signal, 64)
+		conn.bus.Signal(raw)
+		go d.run(raw)
+		dispatchers[conn] = d
+	}
+	return d
+}
+
+func (d *signalDispatcher) run(raw <-chan *dbus.Signal) {
+	for s := range raw {
+		d.mu.Lock()
+		for _, sub := range d.subscribers {
+			if !sub.rule.matches(s) {
+				continue
+			}
+			select {
+			case sub.ch <- s:
+			default:
+				log.Printf("ble: dropping signal %s: subscriber to %s is not keeping up", s.Name, sub.rule)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// subscribe registers rule with the dispatcher and returns a channel of
+// matching signals and a cancel func that unregisters it. AddMatch is
+// only called on the bus for a rule's first subscriber, and RemoveMatch
+// only when its last subscriber cancels.
+func (d *signalDispatcher) subscribe(rule string) (<-chan *dbus.Signal, func(), error) {
+	d.mu.Lock()
+	if d.refCount[rule] == 0 {
+		if err := d.conn.addMatch(rule); err != nil {
+			d.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+	d.refCount[rule]++
+	id := d.nextID
+	d.nextID++
+	ch := make(chan *dbus.Signal, 16)
+	d.subscribers[id] = &ruleSubscriber{rule: parseSignalRule(rule), ch: ch}
+	d.mu.Unlock()
+
+	cancel := func() {
+		d.mu.Lock()
+		delete(d.subscribers, id)
+		d.refCount[rule]--
+		last := d.refCount[rule] <= 0
+		if last {
+			delete(d.refCount, rule)
+		}
+		d.mu.Unlock()
+		if last {
+			_ = d.conn.removeMatch(rule)
+		}
+		close(ch)
+	}
+
+	return ch, cancel, nil
+}
+
+// Subscribe registers rule as a D-Bus match rule and returns a channel
+// carrying every signal matching it, along with a cancel func to stop
+// receiving and unregister the rule once the last subscriber is gone.
+func (conn *Connection) Subscribe(rule string) (<-chan *dbus.Signal, func(), error) {
+	return conn.dispatcher().subscribe(rule)
+}
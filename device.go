@@ -28,6 +28,10 @@ type Device interface {
 
 	WatchProperties(context.Context, func(props Properties)) error
 	ServiceData() map[string]dbus.Variant
+
+	// Services returns the GATT services the device exposes. Populating
+	// them requires the device to be connected and its services resolved.
+	Services() ([]GattService, error)
 }
 
 func (conn *Connection) matchDevice(matching predicate) (Device, error) {
@@ -104,19 +108,11 @@ func (device *blob) WatchProperties(ctx context.Context, h func(props Properties
 		device.Path(),
 	)
 
-	err := device.conn.addMatch(rule)
+	c, cancel, err := device.conn.Subscribe(rule)
 	if err != nil {
 		return err
 	}
-
-	c := make(chan *dbus.Signal, 10)
-	device.conn.bus.Signal(c)
-
-	defer func() {
-		_ = device.conn.removeMatch(rule)
-		device.conn.bus.RemoveSignal(c)
-		close(c)
-	}()
+	defer cancel()
 
 	for {
 		select {
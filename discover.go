@@ -1,40 +1,24 @@
 package ble
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/godbus/dbus"
 )
 
-func addMatch(rule string) error {
-	return bus.BusObject().Call(
-		"org.freedesktop.DBus.AddMatch",
-		0,
-		rule,
-	).Err
-}
-
-func removeMatch(rule string) error {
-	return bus.BusObject().Call(
-		"org.freedesktop.DBus.RemoveMatch",
-		0,
-		rule,
-	).Err
-}
-
 func (adapter *blob) Discover(timeout time.Duration, uuids ...string) error {
-	signals := make(chan *dbus.Signal)
-	defer close(signals)
-	bus.Signal(signals)
-	defer bus.RemoveSignal(signals)
 	rule := "type='signal',interface='org.freedesktop.DBus.ObjectManager',member='InterfacesAdded'"
-	err := addMatch(rule)
+	signals, cancel, err := adapter.conn.Subscribe(rule)
 	if err != nil {
 		return err
 	}
-	defer removeMatch(rule)
+	defer cancel()
 	err = adapter.SetDiscoveryFilter(uuids...)
 	if err != nil {
 		return err
@@ -79,6 +63,190 @@ func containsDevice(s *dbus.Signal) bool {
 	return dict[deviceInterface] != nil
 }
 
+// ErrScanning is returned by Scan when a scan is already running on the
+// adapter.
+var ErrScanning = errors.New("ble: scan already in progress")
+
+// DiscoveryFilter narrows which devices a Scan reports, wrapping the
+// filter BlueZ's SetDiscoveryFilter accepts.
+type DiscoveryFilter struct {
+	UUIDs []string
+}
+
+// ScanResult reports one observation of an advertising device: either its
+// initial discovery or a later RSSI/advertisement refresh.
+type ScanResult struct {
+	Address          string
+	Name             string
+	RSSI             int16
+	TxPower          int16
+	UUIDs            []string
+	ManufacturerData map[uint16]dbus.Variant
+	ServiceData      map[string]dbus.Variant
+}
+
+var scanningAdapters struct {
+	sync.Mutex
+	paths map[dbus.ObjectPath]bool
+}
+
+func beginScan(path dbus.ObjectPath) bool {
+	scanningAdapters.Lock()
+	defer scanningAdapters.Unlock()
+	if scanningAdapters.paths == nil {
+		scanningAdapters.paths = map[dbus.ObjectPath]bool{}
+	}
+	if scanningAdapters.paths[path] {
+		return false
+	}
+	scanningAdapters.paths[path] = true
+	return true
+}
+
+func endScan(path dbus.ObjectPath) {
+	scanningAdapters.Lock()
+	defer scanningAdapters.Unlock()
+	delete(scanningAdapters.paths, path)
+}
+
+// Scan streams every advertisement BlueZ reports for the adapter, rather
+// than blocking until the first matching device appears like Discover
+// does. It fails with ErrScanning if a scan is already running on this
+// adapter. When ctx is cancelled it stops discovery, cancels its signal
+// subscriptions and closes the result channel.
+func (adapter *blob) Scan(ctx context.Context, filter DiscoveryFilter) (<-chan ScanResult, error) {
+	if !beginScan(adapter.Path()) {
+		return nil, ErrScanning
+	}
+
+	addedSignals, cancelAdded, err := adapter.conn.Subscribe(
+		"type='signal',interface='org.freedesktop.DBus.ObjectManager',member='InterfacesAdded'",
+	)
+	if err != nil {
+		endScan(adapter.Path())
+		return nil, err
+	}
+	changedSignals, cancelChanged, err := adapter.conn.Subscribe(
+		"type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'",
+	)
+	if err != nil {
+		cancelAdded()
+		endScan(adapter.Path())
+		return nil, err
+	}
+
+	if err := adapter.SetDiscoveryFilter(filter.UUIDs...); err != nil {
+		cancelAdded()
+		cancelChanged()
+		endScan(adapter.Path())
+		return nil, err
+	}
+	if err := adapter.StartDiscovery(); err != nil {
+		cancelAdded()
+		cancelChanged()
+		endScan(adapter.Path())
+		return nil, err
+	}
+
+	results := make(chan ScanResult, 16)
+	go func() {
+		defer func() {
+			_ = adapter.StopDiscovery()
+			cancelAdded()
+			cancelChanged()
+			close(results)
+			endScan(adapter.Path())
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case s, ok := <-addedSignals:
+				if !ok {
+					return
+				}
+				path, props, ok := deviceAddedProps(s)
+				if !ok {
+					continue
+				}
+				sendResult(ctx, results, scanResultFromProps(addressFromPath(path), props))
+			case s, ok := <-changedSignals:
+				if !ok {
+					return
+				}
+				props, ok := devicePropertiesChanged(s)
+				if !ok {
+					continue
+				}
+				sendResult(ctx, results, scanResultFromProps(addressFromPath(s.Path), props))
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+func sendResult(ctx context.Context, out chan<- ScanResult, r ScanResult) {
+	select {
+	case out <- r:
+	case <-ctx.Done():
+	}
+}
+
+func deviceAddedProps(s *dbus.Signal) (dbus.ObjectPath, map[string]dbus.Variant, bool) {
+	var path dbus.ObjectPath
+	var dict map[string]map[string]dbus.Variant
+	if err := dbus.Store(s.Body[0:1], &path); err != nil {
+		return "", nil, false
+	}
+	if err := dbus.Store(s.Body[1:2], &dict); err != nil {
+		return "", nil, false
+	}
+	props, ok := dict[deviceInterface]
+	return path, props, ok
+}
+
+func devicePropertiesChanged(s *dbus.Signal) (map[string]dbus.Variant, bool) {
+	var iface string
+	if err := dbus.Store(s.Body[0:1], &iface); err != nil || iface != deviceInterface {
+		return nil, false
+	}
+	var changed map[string]dbus.Variant
+	_ = dbus.Store(s.Body[1:2], &changed)
+	return changed, true
+}
+
+// addressFromPath recovers a device's address from its object path, e.g.
+// .../dev_AA_BB_CC_DD_EE_FF -> AA:BB:CC:DD:EE:FF.
+func addressFromPath(path dbus.ObjectPath) string {
+	parts := strings.Split(string(path), "/")
+	last := strings.TrimPrefix(parts[len(parts)-1], "dev_")
+	return strings.ReplaceAll(last, "_", ":")
+}
+
+func scanResultFromProps(addr string, props map[string]dbus.Variant) ScanResult {
+	r := ScanResult{Address: addr}
+	if v, ok := props["Name"]; ok {
+		r.Name, _ = v.Value().(string)
+	}
+	if v, ok := props["RSSI"]; ok {
+		r.RSSI, _ = v.Value().(int16)
+	}
+	if v, ok := props["TxPower"]; ok {
+		r.TxPower, _ = v.Value().(int16)
+	}
+	if v, ok := props["UUIDs"]; ok {
+		r.UUIDs, _ = v.Value().([]string)
+	}
+	if v, ok := props["ManufacturerData"]; ok {
+		r.ManufacturerData, _ = v.Value().(map[uint16]dbus.Variant)
+	}
+	if v, ok := props["ServiceData"]; ok {
+		r.ServiceData, _ = v.Value().(map[string]dbus.Variant)
+	}
+	return r
+}
+
 func (cache *ObjectCache) Discover(timeout time.Duration, uuids ...string) (Device, error) {
 	device, err := cache.GetDevice(uuids...)
 	if err == nil {
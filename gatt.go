@@ -0,0 +1,199 @@
+package ble
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	serviceInterface        = "org.bluez.GattService1"
+	characteristicInterface = "org.bluez.GattCharacteristic1"
+	descriptorInterface     = "org.bluez.GattDescriptor1"
+)
+
+// GattService corresponds to the org.bluez.GattService1 interface.
+// See bluez/doc/gatt-api.txt
+type GattService interface {
+	BaseObject
+
+	UUID() string
+	Primary() bool
+
+	Characteristics() ([]GattCharacteristic, error)
+}
+
+// GattCharacteristic corresponds to the org.bluez.GattCharacteristic1 interface.
+// See bluez/doc/gatt-api.txt
+type GattCharacteristic interface {
+	BaseObject
+
+	UUID() string
+	Flags() []string
+
+	ReadValue(offset uint16) ([]byte, error)
+	WriteValue(value []byte, opts map[string]interface{}) error
+
+	// StartNotify subscribes to the Value property and invokes h with
+	// each updated value until ctx is done.
+	StartNotify(ctx context.Context, h func([]byte)) error
+	StopNotify() error
+
+	// AcquireNotify and AcquireWrite hand back the kernel socket BlueZ
+	// uses for the fd-based fast path, along with its negotiated MTU.
+	AcquireNotify() (*os.File, uint16, error)
+	AcquireWrite() (*os.File, uint16, error)
+
+	Descriptors() ([]GattDescriptor, error)
+}
+
+// GattDescriptor corresponds to the org.bluez.GattDescriptor1 interface.
+// See bluez/doc/gatt-api.txt
+type GattDescriptor interface {
+	BaseObject
+
+	UUID() string
+
+	ReadValue(offset uint16) ([]byte, error)
+	WriteValue(value []byte, opts map[string]interface{}) error
+}
+
+// Services returns the GATT services exported by the device.
+func (device *blob) Services() ([]GattService, error) {
+	blobs, err := device.conn.findChildren(serviceInterface, device.Path())
+	if err != nil {
+		return nil, err
+	}
+	services := make([]GattService, len(blobs))
+	for i, b := range blobs {
+		services[i] = b
+	}
+	return services, nil
+}
+
+// Characteristics returns the characteristics exported by the service.
+func (service *blob) Characteristics() ([]GattCharacteristic, error) {
+	blobs, err := service.conn.findChildren(characteristicInterface, service.Path())
+	if err != nil {
+		return nil, err
+	}
+	chars := make([]GattCharacteristic, len(blobs))
+	for i, b := range blobs {
+		chars[i] = b
+	}
+	return chars, nil
+}
+
+// Descriptors returns the descriptors exported by the characteristic.
+func (char *blob) Descriptors() ([]GattDescriptor, error) {
+	blobs, err := char.conn.findChildren(descriptorInterface, char.Path())
+	if err != nil {
+		return nil, err
+	}
+	descs := make([]GattDescriptor, len(blobs))
+	for i, b := range blobs {
+		descs[i] = b
+	}
+	return descs, nil
+}
+
+func (b *blob) UUID() string {
+	return b.properties["UUID"].Value().(string)
+}
+
+func (service *blob) Primary() bool {
+	return service.properties["Primary"].Value().(bool)
+}
+
+func (char *blob) Flags() []string {
+	return char.properties["Flags"].Value().([]string)
+}
+
+// callOn invokes method on the given interface of b, ignoring any reply body.
+func (b *blob) callOn(iface, method string, args ...interface{}) error {
+	return b.conn.bus.Object("org.bluez", b.Path()).Call(iface+"."+method, 0, args...).Err
+}
+
+// getValueOn invokes method on the given interface of b and stores the
+// single []byte reply.
+func (b *blob) getValueOn(iface, method string, args ...interface{}) ([]byte, error) {
+	var value []byte
+	err := b.conn.bus.Object("org.bluez", b.Path()).Call(iface+"."+method, 0, args...).Store(&value)
+	return value, err
+}
+
+func (b *blob) ReadValue(offset uint16) ([]byte, error) {
+	opts := map[string]interface{}{"offset": offset}
+	return b.getValueOn(b.iface, "ReadValue", opts)
+}
+
+func (b *blob) WriteValue(value []byte, opts map[string]interface{}) error {
+	return b.callOn(b.iface, "WriteValue", value, opts)
+}
+
+// StartNotify arranges for h to be called with the characteristic's Value
+// whenever BlueZ reports it changed, reusing the same match-rule/signal
+// plumbing as Device.WatchProperties.
+func (char *blob) StartNotify(ctx context.Context, h func([]byte)) error {
+	rule := fmt.Sprintf(
+		"type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path='%s'",
+		char.Path(),
+	)
+
+	c, cancel, err := char.conn.Subscribe(rule)
+	if err != nil {
+		return err
+	}
+
+	if err := char.callOn(char.iface, "StartNotify"); err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		defer func() {
+			_ = char.callOn(char.iface, "StopNotify")
+			cancel()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-c:
+				var changed map[string]dbus.Variant
+				_ = dbus.Store(sig.Body[1:2], &changed)
+				v, ok := changed["Value"]
+				if !ok {
+					continue
+				}
+				h(v.Value().([]byte))
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (char *blob) StopNotify() error {
+	return char.callOn(char.iface, "StopNotify")
+}
+
+func (char *blob) AcquireNotify() (*os.File, uint16, error) {
+	return char.acquire("AcquireNotify")
+}
+
+func (char *blob) AcquireWrite() (*os.File, uint16, error) {
+	return char.acquire("AcquireWrite")
+}
+
+func (char *blob) acquire(method string) (*os.File, uint16, error) {
+	var fd dbus.UnixFD
+	var mtu uint16
+	err := char.conn.bus.Object("org.bluez", char.Path()).Call(char.iface+"."+method, 0).Store(&fd, &mtu)
+	if err != nil {
+		return nil, 0, err
+	}
+	return os.NewFile(uintptr(fd), method), mtu, nil
+}
@@ -0,0 +1,306 @@
+package ble
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+)
+
+const (
+	gattManagerInterface = "org.bluez.GattManager1"
+	objectManagerIface   = "org.freedesktop.DBus.ObjectManager"
+)
+
+// ReadValueFunc implements a locally-hosted characteristic or
+// descriptor's ReadValue method. offset is the "offset" option BlueZ
+// passes along with the request.
+type ReadValueFunc func(offset int) ([]byte, error)
+
+// WriteValueFunc implements a locally-hosted characteristic or
+// descriptor's WriteValue method.
+type WriteValueFunc func(value []byte, offset int) error
+
+// Descriptor is a locally-hosted GATT descriptor, exported as part of a
+// Characteristic added through Adapter.AddService.
+type Descriptor struct {
+	UUID       string
+	Flags      []string
+	ReadValue  ReadValueFunc
+	WriteValue WriteValueFunc
+}
+
+// Characteristic is a locally-hosted GATT characteristic, exported as
+// part of a Service added through Adapter.AddService.
+type Characteristic struct {
+	UUID        string
+	Flags       []string
+	ReadValue   ReadValueFunc
+	WriteValue  WriteValueFunc
+	StartNotify func() error
+	StopNotify  func() error
+	Descriptors []*Descriptor
+}
+
+// Service is a locally-hosted GATT service registered with BlueZ through
+// Adapter.AddService.
+type Service struct {
+	UUID            string
+	Primary         bool
+	Characteristics []*Characteristic
+}
+
+// gattApplication is the exported object tree backing one adapter's
+// locally-hosted GATT server. BlueZ has no API to patch a registered
+// application in place, so AddService rebuilds and re-registers the whole
+// tree each time it is called.
+type gattApplication struct {
+	sync.Mutex
+	adapter    *blob
+	path       dbus.ObjectPath
+	services   []*Service
+	registered bool
+	exports    []exportedPath
+}
+
+// exportedPath records one (path, interface) pair exported while
+// registering a gattApplication, so unregister can cleanly tear it down.
+type exportedPath struct {
+	path  dbus.ObjectPath
+	iface string
+}
+
+var (
+	gattAppsMu sync.Mutex
+	gattApps   = map[dbus.ObjectPath]*gattApplication{}
+)
+
+func (adapter *blob) gattApp() *gattApplication {
+	gattAppsMu.Lock()
+	defer gattAppsMu.Unlock()
+	app, ok := gattApps[adapter.Path()]
+	if !ok {
+		app = &gattApplication{adapter: adapter, path: dbus.ObjectPath(string(adapter.Path()) + "/app")}
+		gattApps[adapter.Path()] = app
+	}
+	return app
+}
+
+// AddService exports svc's characteristics and descriptors on the bus and
+// (re-)registers the adapter's GATT application with BlueZ. It may be
+// called more than once; each call cleanly unregisters any previously
+// exported application before registering the new one, so advertisements
+// and services can be stopped and restarted without panicking.
+func (adapter *blob) AddService(svc *Service) error {
+	app := adapter.gattApp()
+	app.Lock()
+	defer app.Unlock()
+
+	if err := app.unregister(); err != nil {
+		return err
+	}
+	app.services = append(app.services, svc)
+	if err := app.register(); err != nil {
+		app.services = app.services[:len(app.services)-1]
+		return err
+	}
+	return nil
+}
+
+type exportedCharacteristic struct {
+	ch *Characteristic
+}
+
+func (c *exportedCharacteristic) ReadValue(opts map[string]dbus.Variant) ([]byte, *dbus.Error) {
+	if c.ch.ReadValue == nil {
+		return nil, dbus.MakeFailedError(fmt.Errorf("characteristic %s is not readable", c.ch.UUID))
+	}
+	value, err := c.ch.ReadValue(offsetOf(opts))
+	if err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+	return value, nil
+}
+
+func (c *exportedCharacteristic) WriteValue(value []byte, opts map[string]dbus.Variant) *dbus.Error {
+	if c.ch.WriteValue == nil {
+		return dbus.MakeFailedError(fmt.Errorf("characteristic %s is not writable", c.ch.UUID))
+	}
+	if err := c.ch.WriteValue(value, offsetOf(opts)); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (c *exportedCharacteristic) StartNotify() *dbus.Error {
+	if c.ch.StartNotify == nil {
+		return nil
+	}
+	if err := c.ch.StartNotify(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (c *exportedCharacteristic) StopNotify() *dbus.Error {
+	if c.ch.StopNotify == nil {
+		return nil
+	}
+	if err := c.ch.StopNotify(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+type exportedDescriptor struct {
+	d *Descriptor
+}
+
+func (d *exportedDescriptor) ReadValue(opts map[string]dbus.Variant) ([]byte, *dbus.Error) {
+	if d.d.ReadValue == nil {
+		return nil, dbus.MakeFailedError(fmt.Errorf("descriptor %s is not readable", d.d.UUID))
+	}
+	value, err := d.d.ReadValue(offsetOf(opts))
+	if err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+	return value, nil
+}
+
+func (d *exportedDescriptor) WriteValue(value []byte, opts map[string]dbus.Variant) *dbus.Error {
+	if d.d.WriteValue == nil {
+		return dbus.MakeFailedError(fmt.Errorf("descriptor %s is not writable", d.d.UUID))
+	}
+	if err := d.d.WriteValue(value, offsetOf(opts)); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func offsetOf(opts map[string]dbus.Variant) int {
+	v, ok := opts["offset"]
+	if !ok {
+		return 0
+	}
+	return int(v.Value().(uint16))
+}
+
+// objectManager backs the org.freedesktop.DBus.ObjectManager interface
+// BlueZ queries when registering a GATT application.
+type objectManager struct {
+	managed map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+}
+
+func (o *objectManager) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	return o.managed, nil
+}
+
+func ifacePropsToVariants(spec map[string]map[string]*prop.Prop) map[string]map[string]dbus.Variant {
+	out := make(map[string]map[string]dbus.Variant, len(spec))
+	for iface, props := range spec {
+		values := make(map[string]dbus.Variant, len(props))
+		for name, p := range props {
+			values[name] = dbus.MakeVariant(p.Value)
+		}
+		out[iface] = values
+	}
+	return out
+}
+
+func (app *gattApplication) register() error {
+	app.exports = nil
+	managed := map[dbus.ObjectPath]map[string]map[string]dbus.Variant{}
+
+	for si, svc := range app.services {
+		svcPath := dbus.ObjectPath(fmt.Sprintf("%s/service%d", app.path, si))
+		svcProps := map[string]map[string]*prop.Prop{
+			serviceInterface: {
+				"UUID":    {Value: svc.UUID, Writable: false},
+				"Primary": {Value: svc.Primary, Writable: false},
+			},
+		}
+		if _, err := prop.Export(app.adapter.conn.bus, svcPath, svcProps); err != nil {
+			return err
+		}
+		app.exports = append(app.exports, exportedPath{svcPath, "org.freedesktop.DBus.Properties"})
+		managed[svcPath] = ifacePropsToVariants(svcProps)
+
+		for ci, ch := range svc.Characteristics {
+			chPath := dbus.ObjectPath(fmt.Sprintf("%s/char%d", svcPath, ci))
+			if err := app.adapter.conn.bus.Export(&exportedCharacteristic{ch}, chPath, characteristicInterface); err != nil {
+				return err
+			}
+			app.exports = append(app.exports, exportedPath{chPath, characteristicInterface})
+
+			chProps := map[string]map[string]*prop.Prop{
+				characteristicInterface: {
+					"UUID":    {Value: ch.UUID, Writable: false},
+					"Service": {Value: svcPath, Writable: false},
+					"Flags":   {Value: ch.Flags, Writable: false},
+				},
+			}
+			if _, err := prop.Export(app.adapter.conn.bus, chPath, chProps); err != nil {
+				return err
+			}
+			app.exports = append(app.exports, exportedPath{chPath, "org.freedesktop.DBus.Properties"})
+			managed[chPath] = ifacePropsToVariants(chProps)
+
+			for di, d := range ch.Descriptors {
+				dPath := dbus.ObjectPath(fmt.Sprintf("%s/desc%d", chPath, di))
+				if err := app.adapter.conn.bus.Export(&exportedDescriptor{d}, dPath, descriptorInterface); err != nil {
+					return err
+				}
+				app.exports = append(app.exports, exportedPath{dPath, descriptorInterface})
+
+				dProps := map[string]map[string]*prop.Prop{
+					descriptorInterface: {
+						"UUID":           {Value: d.UUID, Writable: false},
+						"Characteristic": {Value: chPath, Writable: false},
+						"Flags":          {Value: d.Flags, Writable: false},
+					},
+				}
+				if _, err := prop.Export(app.adapter.conn.bus, dPath, dProps); err != nil {
+					return err
+				}
+				app.exports = append(app.exports, exportedPath{dPath, "org.freedesktop.DBus.Properties"})
+				managed[dPath] = ifacePropsToVariants(dProps)
+			}
+		}
+	}
+
+	if err := app.adapter.conn.bus.Export(&objectManager{managed}, app.path, objectManagerIface); err != nil {
+		return err
+	}
+	app.exports = append(app.exports, exportedPath{app.path, objectManagerIface})
+
+	call := app.adapter.conn.bus.Object("org.bluez", app.adapter.Path()).Call(
+		gattManagerInterface+".RegisterApplication", 0, app.path, map[string]dbus.Variant{},
+	)
+	if call.Err != nil {
+		return call.Err
+	}
+	app.registered = true
+	return nil
+}
+
+// unregister tears down whatever register left behind: every object it
+// managed to export, even from a call that failed partway through, and
+// the BlueZ-side application registration if register got far enough to
+// complete it. It is always safe to call, registered or not, so a failed
+// register never leaks exported bus objects into the next AddService.
+func (app *gattApplication) unregister() error {
+	var callErr error
+	if app.registered {
+		call := app.adapter.conn.bus.Object("org.bluez", app.adapter.Path()).Call(
+			gattManagerInterface+".UnregisterApplication", 0, app.path,
+		)
+		callErr = call.Err
+	}
+	for _, e := range app.exports {
+		_ = app.adapter.conn.bus.Export(nil, e.path, e.iface)
+	}
+	app.registered = false
+	app.exports = nil
+	return callErr
+}